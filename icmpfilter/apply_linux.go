@@ -0,0 +1,65 @@
+//go:build linux
+
+package icmpfilter
+
+import (
+	"fmt"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// toIPv4 converts f into the kernel ICMP_FILTER representation used by
+// golang.org/x/net/ipv4, which only covers types 0-31.
+func (f *Filter) toIPv4() ipv4.ICMPFilter {
+	var out ipv4.ICMPFilter
+	for typ := 0; typ < 32; typ++ {
+		if f.WillBlock(typ) {
+			out.Block(ipv4.ICMPType(typ))
+		} else {
+			out.Accept(ipv4.ICMPType(typ))
+		}
+	}
+	return out
+}
+
+// toIPv6 converts f into the kernel ICMPV6_FILTER representation used
+// by golang.org/x/net/ipv6, which covers the full 0-255 type range.
+func (f *Filter) toIPv6() ipv6.ICMPFilter {
+	var out ipv6.ICMPFilter
+	for typ := 0; typ < 256; typ++ {
+		if f.WillBlock(typ) {
+			out.Block(ipv6.ICMPType(typ))
+		} else {
+			out.Accept(ipv6.ICMPType(typ))
+		}
+	}
+	return out
+}
+
+// Apply installs f as a kernel-side filter on pc via setsockopt, using
+// IP_ICMP_FILTER for raw IPv4 ICMP sockets and IPV6_ICMP_FILTER for
+// IPv6 ICMP sockets (raw or unprivileged "udp6"). It reports whether a
+// kernel filter was installed; when it returns false, ok is false and
+// callers should fall back to filtering in userspace after ReadFrom.
+func (f *Filter) Apply(pc *icmp.PacketConn) (ok bool, err error) {
+	if p6 := pc.IPv6PacketConn(); p6 != nil {
+		filt := f.toIPv6()
+		if err := p6.SetICMPFilter(&filt); err != nil {
+			return false, fmt.Errorf("icmpfilter: setting IPV6_ICMP_FILTER: %w", err)
+		}
+		return true, nil
+	}
+
+	// IPv4 ICMP_FILTER is only settable on a raw ICMP socket.
+	raw, err := ipv4.NewRawConn(pc)
+	if err != nil {
+		return false, nil
+	}
+	filt := f.toIPv4()
+	if err := raw.SetICMPFilter(&filt); err != nil {
+		return false, fmt.Errorf("icmpfilter: setting ICMP_FILTER: %w", err)
+	}
+	return true, nil
+}
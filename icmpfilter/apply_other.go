@@ -0,0 +1,12 @@
+//go:build !linux
+
+package icmpfilter
+
+import "golang.org/x/net/icmp"
+
+// Apply is a no-op on platforms without a kernel ICMP filter
+// setsockopt. It always reports ok=false so callers fall back to
+// filtering in userspace after ReadFrom.
+func (f *Filter) Apply(pc *icmp.PacketConn) (ok bool, err error) {
+	return false, nil
+}
@@ -0,0 +1,53 @@
+// Package icmpfilter provides a cross-platform wrapper around the
+// kernel ICMP type filters (Linux's ICMP_FILTER / ICMPV6_FILTER
+// setsockopt) so callers can subscribe to a subset of ICMP message
+// types without processing every packet that reaches the socket.
+package icmpfilter
+
+// Filter is a bitmap of ICMP types, one bit per type (0-255), where a
+// set bit means "blocked". This mirrors the semantics of the Linux
+// icmp_filter/icmp6_filter kernel structs, generalized to 8 words so a
+// single Filter can describe either an IPv4 or an IPv6 type space.
+type Filter struct {
+	bits [8]uint32
+}
+
+// New returns a Filter that accepts every type.
+func New() *Filter {
+	return &Filter{}
+}
+
+func wordBit(typ int) (word int, bit uint) {
+	typ &= 0xff
+	return typ >> 5, uint(typ & 31)
+}
+
+// Accept marks typ as not blocked.
+func (f *Filter) Accept(typ int) {
+	word, bit := wordBit(typ)
+	f.bits[word] &^= 1 << bit
+}
+
+// Block marks typ as blocked.
+func (f *Filter) Block(typ int) {
+	word, bit := wordBit(typ)
+	f.bits[word] |= 1 << bit
+}
+
+// SetAll blocks every type when block is true, or accepts every type
+// when block is false.
+func (f *Filter) SetAll(block bool) {
+	var word uint32
+	if block {
+		word = 0xffffffff
+	}
+	for i := range f.bits {
+		f.bits[i] = word
+	}
+}
+
+// WillBlock reports whether typ is currently blocked by the filter.
+func (f *Filter) WillBlock(typ int) bool {
+	word, bit := wordBit(typ)
+	return f.bits[word]&(1<<bit) != 0
+}
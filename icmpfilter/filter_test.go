@@ -0,0 +1,67 @@
+package icmpfilter
+
+import "testing"
+
+func TestFilterAcceptBlock(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  int
+	}{
+		{"zero", 0},
+		{"within first word", 31},
+		{"second word boundary", 32},
+		{"last type", 255},
+		{"out of range wraps via mask", 256}, // 256 & 0xff == 0
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New()
+			if f.WillBlock(tt.typ) {
+				t.Fatalf("WillBlock(%d) = true on a fresh filter, want false", tt.typ)
+			}
+
+			f.Block(tt.typ)
+			if !f.WillBlock(tt.typ) {
+				t.Fatalf("WillBlock(%d) = false after Block, want true", tt.typ)
+			}
+
+			f.Accept(tt.typ)
+			if f.WillBlock(tt.typ) {
+				t.Fatalf("WillBlock(%d) = true after Accept, want false", tt.typ)
+			}
+		})
+	}
+}
+
+func TestFilterBlockDoesNotAffectOtherTypes(t *testing.T) {
+	f := New()
+	f.Block(3) // DestinationUnreachable
+
+	if !f.WillBlock(3) {
+		t.Fatalf("WillBlock(3) = false, want true")
+	}
+	for _, typ := range []int{0, 8, 11, 31, 32, 129} {
+		if f.WillBlock(typ) {
+			t.Errorf("WillBlock(%d) = true, want false (only type 3 was blocked)", typ)
+		}
+	}
+}
+
+func TestFilterSetAll(t *testing.T) {
+	f := New()
+
+	f.SetAll(true)
+	for _, typ := range []int{0, 31, 32, 63, 200, 255} {
+		if !f.WillBlock(typ) {
+			t.Errorf("after SetAll(true): WillBlock(%d) = false, want true", typ)
+		}
+	}
+
+	f.SetAll(false)
+	for _, typ := range []int{0, 31, 32, 63, 200, 255} {
+		if f.WillBlock(typ) {
+			t.Errorf("after SetAll(false): WillBlock(%d) = true, want false", typ)
+		}
+	}
+}
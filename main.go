@@ -1,12 +1,105 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"os"
+	"time"
 
-	"golang.org/x/net/icmp"
+	"github.com/Nameless-86/ci-1/pinger"
 )
 
 func main() {
-	icmp.ListenPacket("udp", "0.0.0.0:0")
-	fmt.Println("Listening for ICMP packets")
+	if len(os.Args) > 1 && os.Args[1] == "trace" {
+		runTrace(os.Args[2:])
+		return
+	}
+	runPing(os.Args[1:])
+}
+
+func runPing(args []string) {
+	fs := flag.NewFlagSet("ping", flag.ExitOnError)
+	count := fs.Int("c", 4, "number of echo requests to send per target")
+	interval := fs.Duration("i", time.Second, "interval between requests to the same target")
+	timeout := fs.Duration("W", time.Second, "time to wait for a reply before declaring loss")
+	size := fs.Int("s", 32, "number of payload bytes to send")
+	fs.Parse(args)
+
+	targets := fs.Args()
+	if len(targets) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: ping [-c count] [-i interval] [-W timeout] [-s size] target [target...]")
+		os.Exit(2)
+	}
+
+	opts := pinger.Options{
+		Count:    *count,
+		Interval: *interval,
+		Timeout:  *timeout,
+		Size:     *size,
+	}
+
+	results, err := pinger.Ping(context.Background(), targets, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ping:", err)
+		os.Exit(1)
+	}
+
+	for _, s := range results {
+		fmt.Printf("--- %s ping statistics ---\n", s.Target)
+		fmt.Printf("%d packets transmitted, %d received, %.1f%% packet loss\n",
+			s.Sent, s.Received, s.PacketsLoss)
+		if s.Received > 0 {
+			fmt.Printf("rtt min/avg/max/stddev = %v/%v/%v/%v\n", s.Min, s.Avg, s.Max, s.StdDev)
+		}
+	}
+}
+
+func runTrace(args []string) {
+	fs := flag.NewFlagSet("trace", flag.ExitOnError)
+	maxHops := fs.Int("m", 30, "maximum number of hops to probe")
+	probes := fs.Int("q", 3, "number of probes per hop")
+	timeout := fs.Duration("w", time.Second, "time to wait for a reply to a single probe")
+	udp := fs.Bool("u", false, "use UDP probes instead of ICMP echo requests")
+	resolveNames := fs.Bool("n", false, "disable reverse DNS lookups for each hop")
+	fs.Parse(args)
+
+	dests := fs.Args()
+	if len(dests) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: trace [-m maxhops] [-q probes] [-w timeout] [-u] [-n] dest")
+		os.Exit(2)
+	}
+
+	opts := pinger.TraceOptions{
+		MaxHops:      *maxHops,
+		Probes:       *probes,
+		Timeout:      *timeout,
+		UDP:          *udp,
+		ResolveNames: !*resolveNames,
+	}
+
+	hops, err := pinger.Trace(context.Background(), dests[0], opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "trace:", err)
+		os.Exit(1)
+	}
+
+	for _, hop := range hops {
+		label := "*"
+		if hop.Addr != nil {
+			label = hop.Addr.String()
+			if hop.Name != "" {
+				label = fmt.Sprintf("%s (%s)", hop.Name, label)
+			}
+		}
+		fmt.Printf("%2d  %s", hop.TTL, label)
+		for _, p := range hop.Probes {
+			if p.TimedOut {
+				fmt.Print("  *")
+				continue
+			}
+			fmt.Printf("  %v", p.RTT)
+		}
+		fmt.Println()
+	}
 }
@@ -0,0 +1,228 @@
+package pinger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/Nameless-86/ci-1/icmpfilter"
+)
+
+// Handler is called for each decoded ICMP message matching the type it
+// was registered for.
+type Handler func(msg *icmp.Message, peer net.Addr)
+
+// Counters tracks Listener activity, suitable for periodic scraping.
+type Counters struct {
+	Received    uint64
+	ParseErrors uint64
+	ByType      map[int]uint64
+}
+
+// Listener owns an *icmp.PacketConn and dispatches decoded messages to
+// per-type handlers registered via OnEcho, OnEchoReply,
+// OnDestinationUnreachable, OnTimeExceeded and On. It is a reusable,
+// event-driven replacement for a one-shot ListenPacket call: other
+// packages in this module can embed a Listener to receive ICMP traffic
+// without writing their own read loop.
+type Listener struct {
+	pc     *icmp.PacketConn
+	proto  int
+	isIPv6 bool
+
+	mu       sync.Mutex
+	handlers map[ipv4.ICMPType][]Handler // keyed by the numeric type, shared across v4/v6
+
+	// filter is applied in userspace in serve() when it could not be
+	// installed as a kernel-side setsockopt filter.
+	filter *icmpfilter.Filter
+
+	received    uint64
+	parseErrors uint64
+	byType      sync.Map // int -> *uint64
+
+	errMu sync.Mutex
+	err   error
+
+	done chan struct{}
+}
+
+// NewListener opens an ICMP socket on network/address (e.g. "udp4",
+// "0.0.0.0:0" or "ip6:ipv6-icmp", "::") and returns a Listener ready to
+// Start. Unlike the bare icmp.ListenPacket call, any error is returned
+// rather than discarded. If filter is non-nil, it is installed as a
+// kernel-side setsockopt filter where supported (the same as
+// openConn), or applied in userspace in serve() otherwise, so callers
+// can subscribe to only the ICMP types they care about.
+func NewListener(network, address string, filter *icmpfilter.Filter) (*Listener, error) {
+	pc, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("pinger: listening on %s %s: %w", network, address, err)
+	}
+
+	proto := protocolICMP
+	isIPv6 := false
+	switch network {
+	case "udp6", "ip6:ipv6-icmp":
+		proto = protocolIPv6ICMP
+		isIPv6 = true
+	}
+
+	l := &Listener{
+		pc:       pc,
+		proto:    proto,
+		isIPv6:   isIPv6,
+		handlers: make(map[ipv4.ICMPType][]Handler),
+		done:     make(chan struct{}),
+	}
+
+	if filter != nil {
+		applied, err := filter.Apply(pc)
+		if err != nil {
+			pc.Close()
+			return nil, fmt.Errorf("pinger: applying filter: %w", err)
+		}
+		if !applied {
+			l.filter = filter
+		}
+	}
+
+	return l, nil
+}
+
+// OnEcho registers h to run for incoming Echo Request messages.
+func (l *Listener) OnEcho(h Handler) {
+	l.on(ipv4.ICMPTypeEcho, ipv6.ICMPTypeEchoRequest, h)
+}
+
+// OnEchoReply registers h to run for incoming Echo Reply messages.
+func (l *Listener) OnEchoReply(h Handler) {
+	l.on(ipv4.ICMPTypeEchoReply, ipv6.ICMPTypeEchoReply, h)
+}
+
+// OnDestinationUnreachable registers h to run for incoming Destination
+// Unreachable messages.
+func (l *Listener) OnDestinationUnreachable(h Handler) {
+	l.on(ipv4.ICMPTypeDestinationUnreachable, ipv6.ICMPTypeDestinationUnreachable, h)
+}
+
+// OnTimeExceeded registers h to run for incoming Time Exceeded
+// messages.
+func (l *Listener) OnTimeExceeded(h Handler) {
+	l.on(ipv4.ICMPTypeTimeExceeded, ipv6.ICMPTypeTimeExceeded, h)
+}
+
+// On registers h to run for incoming messages of the given ICMPv4
+// type, or its ICMPv6 equivalent if the Listener was opened on an IPv6
+// socket. Use this for types without a dedicated On* method.
+func (l *Listener) On(v4Type ipv4.ICMPType, v6Type ipv6.ICMPType, h Handler) {
+	l.on(v4Type, v6Type, h)
+}
+
+func (l *Listener) on(v4Type ipv4.ICMPType, v6Type ipv6.ICMPType, h Handler) {
+	key := v4Type
+	if l.isIPv6 {
+		key = ipv4.ICMPType(v6Type)
+	}
+	l.mu.Lock()
+	l.handlers[key] = append(l.handlers[key], h)
+	l.mu.Unlock()
+}
+
+// Start launches the read loop in a new goroutine. It returns
+// immediately; the loop runs until ctx is canceled, at which point it
+// closes the underlying connection to unblock ReadFrom and exits. Wait
+// blocks until the loop has fully stopped.
+func (l *Listener) Start(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		l.pc.Close()
+	}()
+	go l.serve()
+}
+
+func (l *Listener) serve() {
+	defer close(l.done)
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := l.pc.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.setErr(err)
+			}
+			return
+		}
+
+		rm, err := icmp.ParseMessage(l.proto, buf[:n])
+		if err != nil {
+			atomic.AddUint64(&l.parseErrors, 1)
+			continue
+		}
+		if l.filter != nil && l.filter.WillBlock(icmpTypeNumber(rm.Type)) {
+			continue
+		}
+		atomic.AddUint64(&l.received, 1)
+
+		typ, ok := rm.Type.(ipv4.ICMPType)
+		if !ok {
+			if t, ok := rm.Type.(ipv6.ICMPType); ok {
+				typ = ipv4.ICMPType(t)
+			}
+		}
+		l.countType(int(typ))
+
+		l.mu.Lock()
+		handlers := append([]Handler(nil), l.handlers[typ]...)
+		l.mu.Unlock()
+		for _, h := range handlers {
+			h(rm, peer)
+		}
+	}
+}
+
+func (l *Listener) countType(typ int) {
+	v, _ := l.byType.LoadOrStore(typ, new(uint64))
+	atomic.AddUint64(v.(*uint64), 1)
+}
+
+func (l *Listener) setErr(err error) {
+	l.errMu.Lock()
+	l.err = err
+	l.errMu.Unlock()
+}
+
+// Err returns the error that stopped the read loop, or nil if it is
+// still running (or was stopped cleanly via context cancellation,
+// whose resulting "use of closed network connection" error is
+// suppressed).
+func (l *Listener) Err() error {
+	l.errMu.Lock()
+	defer l.errMu.Unlock()
+	return l.err
+}
+
+// Wait blocks until the read loop has exited.
+func (l *Listener) Wait() {
+	<-l.done
+}
+
+// Counters returns a snapshot of the Listener's activity counters.
+func (l *Listener) Counters() Counters {
+	c := Counters{
+		Received:    atomic.LoadUint64(&l.received),
+		ParseErrors: atomic.LoadUint64(&l.parseErrors),
+		ByType:      make(map[int]uint64),
+	}
+	l.byType.Range(func(k, v interface{}) bool {
+		c.ByType[k.(int)] = atomic.LoadUint64(v.(*uint64))
+		return true
+	})
+	return c
+}
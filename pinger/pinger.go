@@ -0,0 +1,349 @@
+// Package pinger implements a small ping/echo subsystem on top of
+// golang.org/x/net/icmp, reporting per-target round-trip statistics
+// similar to the standard "ping -c" utility.
+package pinger
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/Nameless-86/ci-1/icmpfilter"
+)
+
+// Options controls how Ping probes its targets.
+type Options struct {
+	// Count is the number of echo requests to send per target.
+	Count int
+	// Interval is the delay between successive requests to the same target.
+	Interval time.Duration
+	// Timeout is how long to wait for a reply before counting a request as lost.
+	Timeout time.Duration
+	// Size is the number of payload bytes to send after the timestamp/sequence header.
+	Size int
+	// Filter, if non-nil, restricts which ICMP types the pinger's
+	// listening socket processes. It is applied via setsockopt where
+	// the kernel supports it, or in userspace otherwise.
+	Filter *icmpfilter.Filter
+}
+
+// DefaultOptions returns the options used when none are supplied.
+func DefaultOptions() Options {
+	return Options{
+		Count:    4,
+		Interval: time.Second,
+		Timeout:  time.Second,
+		Size:     32,
+	}
+}
+
+// Stats summarizes the results of pinging a single target.
+type Stats struct {
+	Target      string
+	Addr        net.Addr
+	Sent        int
+	Received    int
+	Min         time.Duration
+	Avg         time.Duration
+	Max         time.Duration
+	StdDev      time.Duration
+	PacketsLoss float64 // percentage in [0, 100]
+}
+
+const (
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+// conn wraps the two socket modes (unprivileged "udpN" and raw "ipN:icmp")
+// that a single pinger may need. Each target gets its own conn so that
+// concurrent targets never share a socket: ReadFrom on a shared
+// *icmp.PacketConn has no way to route a reply to the goroutine
+// waiting for it, so one target's probe can steal another's reply.
+type conn struct {
+	c       *icmp.PacketConn
+	raw     bool
+	network string
+	// id is the value the kernel will echo back in the ID field of a
+	// reply. For a raw socket this is arbitrary (the kernel leaves our
+	// ICMP header untouched), so it's derived from the process ID. For
+	// an unprivileged "udpN" socket, Linux instead overwrites the ID we
+	// send with the socket's local port, so id must be that port or
+	// replies will never match.
+	id int
+	// filter is applied in userspace after ReadFrom when it could not
+	// be installed as a kernel-side setsockopt filter.
+	filter *icmpfilter.Filter
+}
+
+// Ping resolves each target, sends Options.Count echo requests to it and
+// returns aggregate RTT statistics. Targets are probed concurrently, each
+// over its own socket; ctx cancellation stops all outstanding probes for
+// every target.
+func Ping(ctx context.Context, targets []string, opts Options) ([]Stats, error) {
+	if opts.Count <= 0 {
+		opts.Count = DefaultOptions().Count
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultOptions().Interval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultOptions().Timeout
+	}
+	if opts.Size <= 0 {
+		opts.Size = DefaultOptions().Size
+	}
+
+	results := make([]Stats, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		dst, isIPv6, err := resolve(target)
+		if err != nil {
+			results[i] = Stats{Target: target, Sent: opts.Count, PacketsLoss: 100}
+			continue
+		}
+		c, err := openConn(isIPv6, opts.Filter)
+		if err != nil {
+			results[i] = Stats{Target: target, Sent: opts.Count, PacketsLoss: 100}
+			continue
+		}
+		wg.Add(1)
+		i, target, dst, isIPv6 := i, target, dst, isIPv6
+		go func() {
+			defer wg.Done()
+			defer c.c.Close()
+			results[i] = pingOne(ctx, c, target, dst, isIPv6, opts)
+		}()
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// openConn opens a fresh connection for the given address family,
+// preferring an unprivileged "udp" socket and falling back to a raw
+// "ip:icmp" socket if that's unavailable. If filter is non-nil, it is
+// installed as a kernel-side setsockopt filter where supported, or
+// kept on conn for userspace filtering after ReadFrom otherwise.
+func openConn(isIPv6 bool, filter *icmpfilter.Filter) (*conn, error) {
+	udpNetwork, rawNetwork, listenAddr := "udp4", "ip4:icmp", "0.0.0.0"
+	if isIPv6 {
+		udpNetwork, rawNetwork, listenAddr = "udp6", "ip6:ipv6-icmp", "::"
+	}
+
+	var c *conn
+	if pc, err := icmp.ListenPacket(udpNetwork, listenAddr); err == nil {
+		c = &conn{c: pc, raw: false, network: udpNetwork, id: os.Getpid() & 0xffff}
+		if udpAddr, ok := pc.LocalAddr().(*net.UDPAddr); ok {
+			c.id = udpAddr.Port
+		}
+	} else {
+		pc, err := icmp.ListenPacket(rawNetwork, listenAddr)
+		if err != nil {
+			return nil, fmt.Errorf("pinger: opening %s and %s: %w", udpNetwork, rawNetwork, err)
+		}
+		c = &conn{c: pc, raw: true, network: rawNetwork, id: os.Getpid() & 0xffff}
+	}
+
+	if filter != nil {
+		applied, err := filter.Apply(c.c)
+		if err != nil {
+			c.c.Close()
+			return nil, fmt.Errorf("pinger: applying filter: %w", err)
+		}
+		if !applied {
+			c.filter = filter
+		}
+	}
+
+	return c, nil
+}
+
+func resolve(target string) (*net.IPAddr, bool, error) {
+	ipAddr, err := net.ResolveIPAddr("ip", target)
+	if err != nil {
+		return nil, false, err
+	}
+	return ipAddr, ipAddr.IP.To4() == nil, nil
+}
+
+// writeAddr returns the net.Addr to pass to c.c.WriteTo for dst: a
+// *net.UDPAddr over an unprivileged "udpN" socket (as required by
+// icmp.PacketConn.WriteTo), or dst itself over a raw "ipN:icmp" socket.
+func writeAddr(c *conn, dst *net.IPAddr) net.Addr {
+	if c.raw {
+		return dst
+	}
+	return &net.UDPAddr{IP: dst.IP, Zone: dst.Zone}
+}
+
+// sameHost reports whether a (a *net.IPAddr from a raw socket or a
+// *net.UDPAddr from an unprivileged one) refers to the same IP and
+// zone as dst.
+func sameHost(a net.Addr, dst *net.IPAddr) bool {
+	switch v := a.(type) {
+	case *net.IPAddr:
+		return v.IP.Equal(dst.IP) && v.Zone == dst.Zone
+	case *net.UDPAddr:
+		return v.IP.Equal(dst.IP) && v.Zone == dst.Zone
+	default:
+		return false
+	}
+}
+
+// pingOne sends Options.Count echo requests to dst over c and aggregates
+// the observed round-trip times into a Stats value.
+func pingOne(ctx context.Context, c *conn, target string, dst *net.IPAddr, isIPv6 bool, opts Options) Stats {
+	stats := Stats{Target: target, Addr: dst, Min: time.Duration(math.MaxInt64)}
+
+	var rtts []time.Duration
+	for seq := 0; seq < opts.Count; seq++ {
+		select {
+		case <-ctx.Done():
+			stats.Sent++
+			continue
+		default:
+		}
+
+		stats.Sent++
+		rtt, ok := probe(ctx, c, dst, isIPv6, c.id, seq, opts)
+		if ok {
+			stats.Received++
+			rtts = append(rtts, rtt)
+			if rtt < stats.Min {
+				stats.Min = rtt
+			}
+			if rtt > stats.Max {
+				stats.Max = rtt
+			}
+		}
+
+		if seq < opts.Count-1 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(opts.Interval):
+			}
+		}
+	}
+
+	if stats.Sent > 0 {
+		stats.PacketsLoss = 100 * float64(stats.Sent-stats.Received) / float64(stats.Sent)
+	}
+	if len(rtts) == 0 {
+		stats.Min = 0
+		return stats
+	}
+	stats.Avg, stats.StdDev = meanAndStdDev(rtts)
+	return stats
+}
+
+// probe sends a single echo request and waits (up to Options.Timeout) for
+// the matching reply, identified by the ID and sequence number in its
+// echo header.
+func probe(ctx context.Context, c *conn, dst *net.IPAddr, isIPv6 bool, id, seq int, opts Options) (time.Duration, bool) {
+	payload := make([]byte, 16+opts.Size)
+	sent := time.Now()
+	binary.BigEndian.PutUint64(payload[:8], uint64(sent.UnixNano()))
+	binary.BigEndian.PutUint64(payload[8:16], uint64(seq))
+
+	echoType := icmp.Type(ipv4.ICMPTypeEcho)
+	if isIPv6 {
+		echoType = ipv6.ICMPTypeEchoRequest
+	}
+
+	msg := icmp.Message{
+		Type: echoType,
+		Code: 0,
+		Body: &icmp.Echo{
+			ID:   id,
+			Seq:  seq,
+			Data: payload,
+		},
+	}
+	wb, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, false
+	}
+	if _, err := c.c.WriteTo(wb, writeAddr(c, dst)); err != nil {
+		return 0, false
+	}
+
+	deadline := sent.Add(opts.Timeout)
+	c.c.SetReadDeadline(deadline)
+
+	proto := protocolICMP
+	if isIPv6 {
+		proto = protocolIPv6ICMP
+	}
+
+	rb := make([]byte, 1500)
+	for {
+		if time.Now().After(deadline) {
+			return 0, false
+		}
+		n, peer, err := c.c.ReadFrom(rb)
+		if err != nil {
+			return 0, false
+		}
+		rm, err := icmp.ParseMessage(proto, rb[:n])
+		if err != nil {
+			continue
+		}
+		if c.filter != nil && c.filter.WillBlock(icmpTypeNumber(rm.Type)) {
+			continue
+		}
+		echo, ok := rm.Body.(*icmp.Echo)
+		if !ok || echo.ID != id || echo.Seq != seq {
+			continue
+		}
+		if !sameHost(peer, dst) {
+			continue
+		}
+		switch rm.Type {
+		case ipv4.ICMPTypeEchoReply, ipv6.ICMPTypeEchoReply:
+			return time.Since(sent), true
+		default:
+			continue
+		}
+	}
+}
+
+// icmpTypeNumber extracts the numeric ICMP type from the concrete type
+// returned by icmp.ParseMessage, so it can be checked against a
+// userspace icmpfilter.Filter.
+func icmpTypeNumber(t icmp.Type) int {
+	switch t := t.(type) {
+	case ipv4.ICMPType:
+		return int(t)
+	case ipv6.ICMPType:
+		return int(t)
+	default:
+		return -1
+	}
+}
+
+func meanAndStdDev(d []time.Duration) (avg, stddev time.Duration) {
+	var sum float64
+	for _, v := range d {
+		sum += float64(v)
+	}
+	mean := sum / float64(len(d))
+
+	var variance float64
+	for _, v := range d {
+		diff := float64(v) - mean
+		variance += diff * diff
+	}
+	variance /= float64(len(d))
+
+	return time.Duration(mean), time.Duration(math.Sqrt(variance))
+}
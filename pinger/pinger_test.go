@@ -0,0 +1,54 @@
+package pinger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeanAndStdDevConstant(t *testing.T) {
+	tests := []struct {
+		name string
+		rtts []time.Duration
+		want time.Duration
+	}{
+		{
+			name: "single value",
+			rtts: []time.Duration{10 * time.Millisecond},
+			want: 10 * time.Millisecond,
+		},
+		{
+			name: "identical values",
+			rtts: []time.Duration{5 * time.Millisecond, 5 * time.Millisecond, 5 * time.Millisecond},
+			want: 5 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			avg, stddev := meanAndStdDev(tt.rtts)
+			if avg != tt.want {
+				t.Errorf("avg = %v, want %v", avg, tt.want)
+			}
+			if stddev != 0 {
+				t.Errorf("stddev = %v, want 0", stddev)
+			}
+		})
+	}
+}
+
+func TestMeanAndStdDevVarying(t *testing.T) {
+	rtts := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}
+
+	avg, stddev := meanAndStdDev(rtts)
+
+	wantAvg := 20 * time.Millisecond
+	if avg != wantAvg {
+		t.Errorf("avg = %v, want %v", avg, wantAvg)
+	}
+
+	// Population stddev of {10, 20, 30}ms is sqrt(((-10)^2+0^2+10^2)/3)ms ~= 8.164966ms.
+	wantStdDev := 8164966 * time.Nanosecond
+	if diff := stddev - wantStdDev; diff < -time.Microsecond || diff > time.Microsecond {
+		t.Errorf("stddev = %v, want approximately %v", stddev, wantStdDev)
+	}
+}
@@ -0,0 +1,306 @@
+package pinger
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// TraceOptions controls Trace's probing behavior.
+type TraceOptions struct {
+	// MaxHops is the largest TTL/hop limit to try before giving up.
+	MaxHops int
+	// Probes is the number of probes sent per hop.
+	Probes int
+	// Timeout is how long to wait for a response to a single probe.
+	Timeout time.Duration
+	// UDP sends UDP probes to a high port instead of ICMP echo requests,
+	// matching traditional Unix traceroute behavior.
+	UDP bool
+	// ResolveNames looks up a PTR record for each responding hop.
+	ResolveNames bool
+}
+
+// DefaultTraceOptions returns the options used when none are supplied.
+func DefaultTraceOptions() TraceOptions {
+	return TraceOptions{
+		MaxHops: 30,
+		Probes:  3,
+		Timeout: time.Second,
+	}
+}
+
+// ProbeResult is the outcome of a single probe sent at a given TTL.
+type ProbeResult struct {
+	Addr net.Addr
+	RTT  time.Duration
+	// TimedOut is true when no response arrived within TraceOptions.Timeout.
+	TimedOut bool
+}
+
+// Hop is the aggregate result for one TTL value along the path.
+type Hop struct {
+	TTL     int
+	Probes  []ProbeResult
+	Addr    net.Addr
+	Name    string // resolved PTR name, if TraceOptions.ResolveNames was set
+	Reached bool   // true once this hop is (one of) the destination(s)
+}
+
+// Trace performs a Van Jacobson-style traceroute to dest: for each TTL
+// from 1 to MaxHops it sends Probes probes, listens for TimeExceeded
+// replies to learn the hop's address, and stops once the destination
+// responds with an EchoReply (ICMP mode) or a port-unreachable
+// DestinationUnreachable (UDP mode).
+func Trace(ctx context.Context, dest string, opts TraceOptions) ([]Hop, error) {
+	if opts.MaxHops <= 0 {
+		opts.MaxHops = DefaultTraceOptions().MaxHops
+	}
+	if opts.Probes <= 0 {
+		opts.Probes = DefaultTraceOptions().Probes
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTraceOptions().Timeout
+	}
+
+	dst, isIPv6, err := resolve(dest)
+	if err != nil {
+		return nil, fmt.Errorf("pinger: resolving %s: %w", dest, err)
+	}
+
+	rawNetwork, listenAddr := "ip4:icmp", "0.0.0.0"
+	if isIPv6 {
+		rawNetwork, listenAddr = "ip6:ipv6-icmp", "::"
+	}
+	rc, err := icmp.ListenPacket(rawNetwork, listenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("pinger: opening %s: %w", rawNetwork, err)
+	}
+	defer rc.Close()
+
+	var udpConn net.Conn
+	if opts.UDP {
+		udpConn, err = net.Dial(udpNetworkFor(isIPv6), net.JoinHostPort(dst.String(), "33434"))
+		if err != nil {
+			return nil, fmt.Errorf("pinger: opening UDP probe socket: %w", err)
+		}
+		defer udpConn.Close()
+	}
+
+	id := os.Getpid() & 0xffff
+	proto := protocolICMP
+	if isIPv6 {
+		proto = protocolIPv6ICMP
+	}
+
+	var hops []Hop
+	for ttl := 1; ttl <= opts.MaxHops; ttl++ {
+		if err := setTTL(rc, isIPv6, ttl); err != nil {
+			return hops, fmt.Errorf("pinger: setting ttl %d: %w", ttl, err)
+		}
+		if udpConn != nil {
+			var err error
+			if isIPv6 {
+				err = ipv6.NewConn(udpConn).SetHopLimit(ttl)
+			} else {
+				err = ipv4.NewConn(udpConn).SetTTL(ttl)
+			}
+			if err != nil {
+				return hops, fmt.Errorf("pinger: setting ttl %d on UDP probe socket: %w", ttl, err)
+			}
+		}
+
+		hop := Hop{TTL: ttl}
+		for i := 0; i < opts.Probes; i++ {
+			select {
+			case <-ctx.Done():
+				return hops, ctx.Err()
+			default:
+			}
+
+			addr, rtt, reached, err := traceProbe(rc, udpConn, dst, isIPv6, id, ttl, i, proto, opts)
+			if err != nil {
+				hop.Probes = append(hop.Probes, ProbeResult{TimedOut: true})
+				continue
+			}
+			hop.Probes = append(hop.Probes, ProbeResult{Addr: addr, RTT: rtt})
+			if hop.Addr == nil {
+				hop.Addr = addr
+			}
+			if reached {
+				hop.Reached = true
+			}
+		}
+
+		if hop.Addr != nil && opts.ResolveNames {
+			if names, err := net.LookupAddr(hop.Addr.String()); err == nil && len(names) > 0 {
+				hop.Name = names[0]
+			}
+		}
+
+		hops = append(hops, hop)
+		if hop.Reached {
+			break
+		}
+	}
+
+	return hops, nil
+}
+
+func udpNetworkFor(isIPv6 bool) string {
+	if isIPv6 {
+		return "udp6"
+	}
+	return "udp4"
+}
+
+// setTTL sets the outgoing TTL (IPv4) or hop limit (IPv6) on the raw
+// ICMP listener, which doubles as the socket used to send ICMP probes.
+func setTTL(rc *icmp.PacketConn, isIPv6 bool, ttl int) error {
+	if isIPv6 {
+		return ipv6.NewPacketConn(rc).SetHopLimit(ttl)
+	}
+	return ipv4.NewPacketConn(rc).SetTTL(ttl)
+}
+
+// traceProbe sends a single TTL-limited probe (ICMP echo, or UDP to a
+// high port) and waits for either a TimeExceeded from an intermediate
+// hop or the destination's own reply.
+func traceProbe(rc *icmp.PacketConn, udpConn net.Conn, dst net.Addr, isIPv6 bool, id, ttl, seq, proto int, opts TraceOptions) (net.Addr, time.Duration, bool, error) {
+	sent := time.Now()
+
+	if opts.UDP {
+		if _, err := udpConn.Write([]byte("pinger-traceroute")); err != nil {
+			return nil, 0, false, err
+		}
+	} else {
+		echoType := icmp.Type(ipv4.ICMPTypeEcho)
+		if isIPv6 {
+			echoType = ipv6.ICMPTypeEchoRequest
+		}
+		msg := icmp.Message{
+			Type: echoType,
+			Code: 0,
+			Body: &icmp.Echo{ID: id, Seq: ttl*1000 + seq},
+		}
+		wb, err := msg.Marshal(nil)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		if _, err := rc.WriteTo(wb, dst); err != nil {
+			return nil, 0, false, err
+		}
+	}
+
+	var udpSrcPort int
+	if opts.UDP {
+		udpSrcPort = udpConn.LocalAddr().(*net.UDPAddr).Port
+	}
+
+	deadline := sent.Add(opts.Timeout)
+	rc.SetReadDeadline(deadline)
+
+	rb := make([]byte, 1500)
+	for {
+		if time.Now().After(deadline) {
+			return nil, 0, false, fmt.Errorf("pinger: probe timed out")
+		}
+		n, peer, err := rc.ReadFrom(rb)
+		if err != nil {
+			return nil, 0, false, err
+		}
+		rm, err := icmp.ParseMessage(proto, rb[:n])
+		if err != nil {
+			continue
+		}
+
+		switch rm.Type {
+		case ipv4.ICMPTypeTimeExceeded, ipv6.ICMPTypeTimeExceeded:
+			if quotedMatches(rm.Body, isIPv6, opts.UDP, id, ttl, seq, udpSrcPort) {
+				return peer, time.Since(sent), false, nil
+			}
+		case ipv4.ICMPTypeEchoReply, ipv6.ICMPTypeEchoReply:
+			if !opts.UDP && peer.String() == dst.String() {
+				echo, ok := rm.Body.(*icmp.Echo)
+				if ok && echo.ID == id && echo.Seq == ttl*1000+seq {
+					return peer, time.Since(sent), true, nil
+				}
+			}
+		case ipv4.ICMPTypeDestinationUnreachable, ipv6.ICMPTypeDestinationUnreachable:
+			if opts.UDP && peer.String() == dst.String() &&
+				quotedMatches(rm.Body, isIPv6, opts.UDP, id, ttl, seq, udpSrcPort) {
+				return peer, time.Since(sent), true, nil
+			}
+		}
+	}
+}
+
+// quotedMatches reports whether the original-datagram quote carried by a
+// TimeExceeded/DestinationUnreachable body (the IP header plus the first
+// 8 bytes of the original datagram, per RFC 792/4443) belongs to this
+// probe. Without this check, any concurrent ICMP traffic back to this
+// host - another trace, another process's ping, an in-process Listener -
+// would be misattributed as this hop's response.
+func quotedMatches(body icmp.MessageBody, isIPv6 bool, udp bool, id, ttl, seq, udpSrcPort int) bool {
+	var quoted []byte
+	switch b := body.(type) {
+	case *icmp.TimeExceeded:
+		quoted = b.Data
+	case *icmp.DstUnreach:
+		quoted = b.Data
+	default:
+		return false
+	}
+
+	orig := stripIPHeader(quoted, isIPv6)
+	if orig == nil {
+		return false
+	}
+
+	if udp {
+		if len(orig) < 2 {
+			return false
+		}
+		srcPort := int(orig[0])<<8 | int(orig[1])
+		return srcPort == udpSrcPort
+	}
+
+	proto := protocolICMP
+	if isIPv6 {
+		proto = protocolIPv6ICMP
+	}
+	origMsg, err := icmp.ParseMessage(proto, orig)
+	if err != nil {
+		return false
+	}
+	echo, ok := origMsg.Body.(*icmp.Echo)
+	return ok && echo.ID == id && echo.Seq == ttl*1000+seq
+}
+
+// stripIPHeader removes the quoted IP header from data, returning the
+// original transport-layer header/payload that follows it, or nil if
+// data is too short to contain one.
+func stripIPHeader(data []byte, isIPv6 bool) []byte {
+	if isIPv6 {
+		const ipv6HeaderLen = 40
+		if len(data) < ipv6HeaderLen {
+			return nil
+		}
+		return data[ipv6HeaderLen:]
+	}
+
+	if len(data) < 20 {
+		return nil
+	}
+	ihl := int(data[0]&0x0f) * 4
+	if ihl < 20 || len(data) < ihl {
+		return nil
+	}
+	return data[ihl:]
+}